@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/yash1234singh/PCP-Copilot-Dashboard/PCP/src/pcp_parser_go/pcparchive"
+)
+
+// NewArchiveSource picks an ArchiveSource implementation for archiveBase
+// based on PCP_ARCHIVE_BACKEND ("native" or "pmrep", default "pmrep"). The
+// native reader avoids forking pminfo/pmrep per archive, but requires the
+// binary to have been built with `-tags pcp_cgo` against libpcp-dev; if it
+// fails to open, we fall back to the pmrep path rather than failing the
+// whole archive.
+func NewArchiveSource(archiveBase string, config *Config, logger *Logger) (pcparchive.ArchiveSource, error) {
+	if config.ArchiveBackend == "native" {
+		source, err := pcparchive.NewNativeSource(archiveBase)
+		if err == nil {
+			return source, nil
+		}
+		logger.Info(fmt.Sprintf("Warning: native archive backend unavailable (%v), falling back to pmrep", err))
+	}
+
+	return pcparchive.NewPmrepSource(archiveBase)
+}
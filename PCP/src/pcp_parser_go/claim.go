@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// archiveClaims is a process-wide registry of archives currently being
+// worked on. The ingestion service (fsnotify/poll-driven auto-processing)
+// and the manual trigger path (processAllArchives/processArchivesParallel)
+// both pull candidates from WatchDir independently, so without a shared
+// claim an archive landing right as a manual trigger fires could be
+// extracted and exported twice. Every worker must claimArchive before
+// touching a path and releaseArchive when it's done (success or failure).
+var archiveClaims = struct {
+	mu      sync.Mutex
+	claimed map[string]bool
+}{claimed: make(map[string]bool)}
+
+func claimKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// claimArchive reports whether path was successfully claimed by the caller.
+// false means another worker (ingestion or manual trigger) already owns it.
+func claimArchive(path string) bool {
+	key := claimKey(path)
+
+	archiveClaims.mu.Lock()
+	defer archiveClaims.mu.Unlock()
+
+	if archiveClaims.claimed[key] {
+		return false
+	}
+	archiveClaims.claimed[key] = true
+	return true
+}
+
+// releaseArchive gives up a claim taken by claimArchive, regardless of
+// outcome.
+func releaseArchive(path string) {
+	key := claimKey(path)
+
+	archiveClaims.mu.Lock()
+	delete(archiveClaims.claimed, key)
+	archiveClaims.mu.Unlock()
+}
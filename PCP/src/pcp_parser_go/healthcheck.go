@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WaitForInfluxDBReady polls InfluxDB's health endpoint with exponential
+// backoff (capped at 30s) until it returns 200/204, the overall timeout
+// (INFLUXDB_READY_TIMEOUT, default 120s) elapses, or ctx is cancelled. It
+// fails fast on a 4xx auth error instead of retrying, since no amount of
+// waiting fixes a bad token.
+func WaitForInfluxDBReady(ctx context.Context, config *Config, logger *Logger) error {
+	timeout := time.Duration(getEnvInt("INFLUXDB_READY_TIMEOUT", 120)) * time.Second
+	deadline := time.Now().Add(timeout)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	url := strings.TrimRight(config.InfluxDBURL, "/") + "/health"
+
+	backoff := 1 * time.Second
+	const backoffCap = 30 * time.Second
+
+	attempt := 0
+	for {
+		attempt++
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to build health check request: %w", err)
+		}
+		if config.InfluxDBToken != "" {
+			req.Header.Set("Authorization", "Token "+config.InfluxDBToken)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+				logger.Info("InfluxDB is ready", "attempt", attempt, "status", resp.StatusCode)
+				return nil
+			}
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				return fmt.Errorf("InfluxDB rejected auth (status %d), refusing to retry", resp.StatusCode)
+			}
+			logger.Info("InfluxDB not ready yet", "attempt", attempt, "status", resp.StatusCode)
+		} else {
+			logger.Info("InfluxDB health check failed", "attempt", attempt, "error", err.Error())
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("InfluxDB did not become ready within %s", timeout)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > backoffCap {
+			backoff = backoffCap
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// IngestionConfig controls the long-running watch-and-process pipeline.
+type IngestionConfig struct {
+	Workers    int
+	ScanPeriod time.Duration
+}
+
+// LoadIngestionConfig reads ingestion-pipeline settings from the environment.
+func LoadIngestionConfig() IngestionConfig {
+	return IngestionConfig{
+		Workers:    getEnvInt("INGEST_WORKERS", 2),
+		ScanPeriod: time.Duration(getEnvInt("INGEST_SCAN_SECONDS", 10)) * time.Second,
+	}
+}
+
+// ingestionService watches WatchDir for new archives and fans them out to a
+// bounded worker pool, so archives are processed as they land instead of
+// waiting for a manual trigger.
+type ingestionService struct {
+	config   *Config
+	ingestCfg IngestionConfig
+	logger   *Logger
+
+	jobs    chan string
+	queued  map[string]bool
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// RunIngestionService starts the watcher + worker pool and blocks until ctx
+// is cancelled. It picks up archives already present in WatchDir on the
+// first scan, then reacts to fsnotify events for anything that lands later,
+// falling back to a periodic directory scan if the watch cannot be created.
+func RunIngestionService(ctx context.Context, config *Config, logger *Logger) {
+	ingestCfg := LoadIngestionConfig()
+	logger.Info(fmt.Sprintf("Starting ingestion service: %d worker(s), scan fallback every %s", ingestCfg.Workers, ingestCfg.ScanPeriod))
+
+	svc := &ingestionService{
+		config:    config,
+		ingestCfg: ingestCfg,
+		logger:    logger,
+		jobs:      make(chan string, 256),
+		queued:    make(map[string]bool),
+	}
+
+	for i := 0; i < ingestCfg.Workers; i++ {
+		svc.wg.Add(1)
+		go svc.worker(i)
+	}
+
+	svc.scanOnce()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Info(fmt.Sprintf("Warning: fsnotify unavailable (%v), falling back to periodic scan of %s", err, config.WatchDir))
+		svc.runPollingLoop(ctx)
+	} else {
+		defer watcher.Close()
+		if err := watcher.Add(config.WatchDir); err != nil {
+			logger.Info(fmt.Sprintf("Warning: failed to watch %s (%v), falling back to periodic scan", config.WatchDir, err))
+			svc.runPollingLoop(ctx)
+		} else {
+			svc.runWatchLoop(ctx, watcher)
+		}
+	}
+
+	close(svc.jobs)
+	svc.wg.Wait()
+	logger.Info("Ingestion service stopped, all in-flight archives finished")
+}
+
+func (s *ingestionService) runWatchLoop(ctx context.Context, watcher *fsnotify.Watcher) {
+	ticker := time.NewTicker(s.ingestCfg.ScanPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if (event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Rename == fsnotify.Rename) &&
+				hasTarXZSuffix(event.Name) {
+				s.enqueue(event.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Info(fmt.Sprintf("Watcher error: %v", err))
+		case <-ticker.C:
+			// Periodic safety-net scan in case an event was missed.
+			s.scanOnce()
+		}
+	}
+}
+
+func (s *ingestionService) runPollingLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.ingestCfg.ScanPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scanOnce()
+		}
+	}
+}
+
+func (s *ingestionService) scanOnce() {
+	matches, err := filepath.Glob(filepath.Join(s.config.WatchDir, "*.tar.xz"))
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("Warning: scan of %s failed: %v", s.config.WatchDir, err))
+		return
+	}
+	for _, path := range matches {
+		if isInBackoff(path, s.config) {
+			continue
+		}
+		s.enqueue(path)
+	}
+}
+
+func (s *ingestionService) enqueue(path string) {
+	s.mu.Lock()
+	if s.queued[path] {
+		s.mu.Unlock()
+		return
+	}
+	s.queued[path] = true
+	s.mu.Unlock()
+
+	select {
+	case s.jobs <- path:
+	default:
+		s.logger.Info(fmt.Sprintf("Warning: ingestion queue full, deferring %s to next scan", filepath.Base(path)))
+		s.mu.Lock()
+		delete(s.queued, path)
+		s.mu.Unlock()
+	}
+}
+
+func (s *ingestionService) worker(id int) {
+	defer s.wg.Done()
+	for path := range s.jobs {
+		if _, err := os.Stat(path); err != nil {
+			// Already picked up (e.g. by the manual trigger) or removed.
+			s.mu.Lock()
+			delete(s.queued, path)
+			s.mu.Unlock()
+			continue
+		}
+
+		archiveName := filepath.Base(path)
+
+		// The manual trigger path (processAllArchives) pulls from the same
+		// WatchDir, so claim the archive before touching it: if the trigger
+		// got there first, skip rather than double-extract/double-export it.
+		if !claimArchive(path) {
+			s.logger.Info(fmt.Sprintf("[worker %d] %s already claimed elsewhere, skipping", id, archiveName))
+			s.mu.Lock()
+			delete(s.queued, path)
+			s.mu.Unlock()
+			continue
+		}
+
+		s.logger.Info(fmt.Sprintf("[worker %d] picked up %s", id, archiveName))
+
+		extractDir := filepath.Join(s.config.ExtractDir, fmt.Sprintf("ingest-worker-%d", id))
+		if err := processArchive(path, s.config, s.logger, extractDir); err != nil {
+			s.logger.Info(fmt.Sprintf("[worker %d] ✗ failed to process %s: %v", id, archiveName, err))
+			if recordFailureAndDecide(path, s.config, s.logger, err) {
+				quarantineArchive(path, s.config.FailedDir, err, s.logger)
+			}
+		} else {
+			clearAttemptRecord(path, s.config)
+		}
+		releaseArchive(path)
+
+		s.mu.Lock()
+		delete(s.queued, path)
+		s.mu.Unlock()
+	}
+}
+
+// quarantineArchive moves a failed archive into FailedDir along with a
+// sidecar ".error" file describing why it failed, so operators don't have to
+// dig through the log to find out what happened to a specific archive.
+func quarantineArchive(archivePath, failedDir string, cause error, logger *Logger) {
+	archiveName := filepath.Base(archivePath)
+	failedPath := filepath.Join(failedDir, archiveName)
+
+	if err := os.Rename(archivePath, failedPath); err != nil {
+		logger.Info(fmt.Sprintf("Warning: failed to move %s to %s: %v", archiveName, failedDir, err))
+		return
+	}
+
+	sidecarPath := failedPath + ".error"
+	sidecar := fmt.Sprintf("archive: %s\nfailed_at: %s\nerror: %v\n", archiveName, time.Now().Format(time.RFC3339), cause)
+	if err := os.WriteFile(sidecarPath, []byte(sidecar), 0644); err != nil {
+		logger.Info(fmt.Sprintf("Warning: failed to write error sidecar for %s: %v", archiveName, err))
+	}
+
+	logger.Info(fmt.Sprintf("✗ Moved %s to %s with error sidecar", archiveName, failedDir))
+}
+
+func hasTarXZSuffix(name string) bool {
+	return len(name) > 7 && name[len(name)-7:] == ".tar.xz"
+}
+
+// waitForShutdownSignal blocks until SIGINT or SIGTERM is received, then
+// cancels ctx so in-flight work can wind down gracefully.
+func waitForShutdownSignal(cancel context.CancelFunc, logger *Logger) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-sigCh
+	logger.Info(fmt.Sprintf("Received %s, shutting down: no new archives will be accepted, waiting for in-flight work to finish...", sig))
+	cancel()
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Level is a logging severity, ordered low-to-high like most leveled loggers.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelCritical
+)
+
+func (lv Level) String() string {
+	switch lv {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelCritical:
+		return "critical"
+	default:
+		return "info"
+	}
+}
+
+func parseLevel(s string) Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug
+	case "warn", "warning":
+		return LevelWarn
+	case "error":
+		return LevelError
+	case "critical", "fatal":
+		return LevelCritical
+	default:
+		return LevelInfo
+	}
+}
+
+// Logger is a leveled logger that writes structured JSON lines to a
+// size/age-rotated file (via lumberjack) and human-readable lines to the
+// console. PCP_TRACE gates Debug-level output by component, the same way
+// STTRACE lets operators turn on verbose per-subsystem tracing without
+// recompiling (e.g. `PCP_TRACE=extract,influx,validate,pmrep`).
+type Logger struct {
+	file    *lumberjack.Logger
+	fileLog *log.Logger
+	console *log.Logger
+
+	level     Level
+	textFile  bool
+	traceAll  bool
+	traceCats map[string]bool
+}
+
+// NewLogger opens logPath (creating its directory if needed) behind a
+// rotating writer and returns a ready-to-use Logger. LOG_LEVEL
+// (debug/info/warn/error/critical, default info) sets the minimum level;
+// PCP_TRACE additionally enables Debug-level logging for the named
+// components (or every component if PCP_TRACE=all). LOG_FORMAT selects the
+// file output format: "json" (default) for log-shipper-friendly structured
+// lines, or "text" for the same human-readable format used on the console.
+func NewLogger(logPath string) (*Logger, error) {
+	logDir := filepath.Dir(logPath)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   logPath,
+		MaxSize:    getEnvInt("LOG_MAX_SIZE_MB", 100),
+		MaxBackups: getEnvInt("LOG_MAX_BACKUPS", 5),
+		MaxAge:     getEnvInt("LOG_MAX_AGE_DAYS", 28),
+		Compress:   getEnvBool("LOG_COMPRESS", true),
+	}
+
+	traceCats := make(map[string]bool)
+	traceAll := false
+	if raw := os.Getenv("PCP_TRACE"); raw != "" {
+		for _, cat := range strings.Split(raw, ",") {
+			cat = strings.ToLower(strings.TrimSpace(cat))
+			if cat == "all" {
+				traceAll = true
+			} else if cat != "" {
+				traceCats[cat] = true
+			}
+		}
+	}
+
+	return &Logger{
+		file:      file,
+		fileLog:   log.New(file, "", 0),
+		console:   log.New(os.Stdout, "", 0),
+		level:     parseLevel(getEnv("LOG_LEVEL", "info")),
+		textFile:  strings.ToLower(strings.TrimSpace(getEnv("LOG_FORMAT", "json"))) == "text",
+		traceAll:  traceAll,
+		traceCats: traceCats,
+	}, nil
+}
+
+// logLine is the JSON shape written to the log file.
+type logLine struct {
+	Timestamp string                 `json:"ts"`
+	Level     string                 `json:"level"`
+	Component string                 `json:"component,omitempty"`
+	Archive   string                 `json:"archive,omitempty"`
+	Message   string                 `json:"msg"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// kvToFields turns a flat "key", value, "key", value, ... variadic list
+// into a fields map, pulling out "archive" as its own top-level column
+// since it's the dimension operators filter by most often.
+func kvToFields(kv []interface{}) (archive string, fields map[string]interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		if key == "archive" {
+			if s, ok := kv[i+1].(string); ok {
+				archive = s
+				continue
+			}
+		}
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields[key] = kv[i+1]
+	}
+	return archive, fields
+}
+
+// write emits a line at level, unless it's below the configured floor.
+// force bypasses that floor — used by Debug for a category enabled via
+// PCP_TRACE, so turning on tracing for one subsystem doesn't also require
+// dropping LOG_LEVEL to debug globally.
+func (l *Logger) write(level Level, component, msg string, force bool, kv ...interface{}) {
+	if level < l.level && !force {
+		return
+	}
+
+	archive, fields := kvToFields(kv)
+	now := time.Now()
+
+	console := fmt.Sprintf("[%s] %s", now.Format("2006-01-02 15:04:05"), msg)
+	if component != "" {
+		console = fmt.Sprintf("[%s] [%s] %s", now.Format("2006-01-02 15:04:05"), component, msg)
+	}
+
+	if l.textFile {
+		l.fileLog.Println(console)
+	} else {
+		line := logLine{
+			Timestamp: now.Format(time.RFC3339),
+			Level:     level.String(),
+			Component: component,
+			Archive:   archive,
+			Message:   msg,
+			Fields:    fields,
+		}
+		if encoded, err := json.Marshal(line); err == nil {
+			l.fileLog.Println(string(encoded))
+		}
+	}
+
+	l.console.Println(console)
+}
+
+// Debug logs a Debug-level line tagged with component, but only if that
+// component (or "all") was enabled via PCP_TRACE. Enabling a category this
+// way bypasses LOG_LEVEL entirely, matching STTRACE's "flip on one
+// subsystem without touching the global level" behavior.
+func (l *Logger) Debug(component, msg string, kv ...interface{}) {
+	if !l.traceAll && !l.traceCats[component] {
+		return
+	}
+	l.write(LevelDebug, component, msg, true, kv...)
+}
+
+// Info logs an Info-level line. kv is an optional flat list of key/value
+// pairs, e.g. logger.Info("extracted", "path", targetDir, "seconds", elapsed).
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.write(LevelInfo, "", msg, false, kv...)
+}
+
+// Warn logs a Warn-level line.
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.write(LevelWarn, "", msg, false, kv...)
+}
+
+// Error logs an Error-level line.
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.write(LevelError, "", msg, false, kv...)
+}
+
+// Critical logs a Critical-level line, for failures an operator should page
+// on rather than merely notice in a log scrape (e.g. InfluxDB unreachable
+// for the whole readiness timeout, WAL directory unwritable).
+func (l *Logger) Critical(msg string, kv ...interface{}) {
+	l.write(LevelCritical, "", msg, false, kv...)
+}
+
+// Separator prints a banner, useful for visually breaking up console/log
+// output between processing stages.
+func (l *Logger) Separator(title string) {
+	l.Info(strings.Repeat("=", 60))
+	l.Info(title)
+	l.Info(strings.Repeat("=", 60))
+}
+
+// Close flushes and closes the underlying rotated log file.
+func (l *Logger) Close() {
+	if l.file != nil {
+		l.file.Close()
+	}
+}
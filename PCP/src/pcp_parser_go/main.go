@@ -2,18 +2,23 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/yash1234singh/PCP-Copilot-Dashboard/PCP/src/pcp_parser_go/pcparchive"
 )
 
 // Configuration from environment variables
@@ -50,59 +55,36 @@ type Config struct {
 	EnableKernelMetrics  bool
 	EnableSwapMetrics    bool
 	EnableNFSMetrics     bool
-}
-
-// Global metrics cache
-var metricsCache = make(map[string]bool)
-
-// Logger wrapper
-type Logger struct {
-	file    *os.File
-	logger  *log.Logger
-	console *log.Logger
-}
-
-func NewLogger(logPath string) (*Logger, error) {
-	// Ensure log directory exists
-	logDir := filepath.Dir(logPath)
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	// Open log file
-	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
-	}
 
-	// Create loggers
-	fileLogger := log.New(file, "", 0)
-	consoleLogger := log.New(os.Stdout, "", 0)
-
-	return &Logger{
-		file:    file,
-		logger:  fileLogger,
-		console: consoleLogger,
-	}, nil
-}
-
-func (l *Logger) Info(msg string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	formattedMsg := fmt.Sprintf("[%s] %s", timestamp, msg)
-	l.logger.Println(formattedMsg)
-	l.console.Println(msg)
-}
-
-func (l *Logger) Separator(title string) {
-	l.Info(strings.Repeat("=", 60))
-	l.Info(title)
-	l.Info(strings.Repeat("=", 60))
+	// ArchiveBackend selects how archives are read: "native" opens the
+	// .meta/.0/.index triplet directly via pcparchive, "pmrep" (default)
+	// shells out to pminfo/pmrep per archive like before.
+	ArchiveBackend string
+
+	// MetricRulesFile, when set, points at a YAML file of ordered
+	// match/action/rename/tags rules (see metricrules.go). MetricRules is
+	// populated from it (or synthesized from ENABLE_*_METRICS) by
+	// LoadMetricRules.
+	MetricRulesFile string
+	MetricRules     *MetricRuleSet
+
+	// OutputSinks is the parsed form of OUTPUT_SINK (comma-separated), e.g.
+	// "influxdb2,kafka". See sinks.go.
+	OutputSinks []string
 }
 
-func (l *Logger) Close() {
-	if l.file != nil {
-		l.file.Close()
-	}
+// Global metrics cache. Guarded by metricsCacheMu since processAllArchives
+// processes archives concurrently (see processArchivesParallel).
+var metricsCache = make(map[string]bool)
+var metricsCacheMu sync.Mutex
+
+// metricsCacheLen returns len(metricsCache) under metricsCacheMu, since the
+// ingestion service's workers mutate the map concurrently via
+// saveMetricToCSV.
+func metricsCacheLen() int {
+	metricsCacheMu.Lock()
+	defer metricsCacheMu.Unlock()
+	return len(metricsCache)
 }
 
 // Load configuration from environment
@@ -139,6 +121,12 @@ func LoadConfig() *Config {
 		EnableKernelMetrics:  getEnvBool("ENABLE_KERNEL_METRICS", true),
 		EnableSwapMetrics:    getEnvBool("ENABLE_SWAP_METRICS", true),
 		EnableNFSMetrics:     getEnvBool("ENABLE_NFS_METRICS", false),
+
+		ArchiveBackend: getEnv("PCP_ARCHIVE_BACKEND", "pmrep"),
+
+		MetricRulesFile: getEnv("METRIC_RULES_FILE", ""),
+
+		OutputSinks: strings.Split(getEnv("OUTPUT_SINK", "influxdb2"), ","),
 	}
 }
 
@@ -238,7 +226,9 @@ func loadMetricsCache(csvPath string) error {
 			return err
 		}
 		if len(record) > 0 {
+			metricsCacheMu.Lock()
 			metricsCache[record[0]] = true
+			metricsCacheMu.Unlock()
 		}
 	}
 
@@ -247,12 +237,16 @@ func loadMetricsCache(csvPath string) error {
 
 // Save metric to CSV if not already tracked
 func saveMetricToCSV(metric, csvPath string) error {
-	if metricsCache[metric] {
-		return nil // Already tracked
+	metricsCacheMu.Lock()
+	alreadyTracked := metricsCache[metric]
+	if !alreadyTracked {
+		metricsCache[metric] = true
 	}
+	metricsCacheMu.Unlock()
 
-	// Add to cache
-	metricsCache[metric] = true
+	if alreadyTracked {
+		return nil // Already tracked
+	}
 
 	// Check if file exists
 	fileExists := false
@@ -305,7 +299,8 @@ func extractArchive(archivePath, extractDir string, logger *Logger) (string, err
 	}
 
 	elapsed := time.Since(startTime).Seconds()
-	logger.Info(fmt.Sprintf("Extracted to %s in %.2f seconds", targetDir, elapsed))
+	logger.Debug("extract", "extracted archive", "path", targetDir, "seconds", elapsed)
+	logger.Info("Extracted archive", "path", targetDir, "seconds", elapsed)
 
 	return targetDir, nil
 }
@@ -415,7 +410,7 @@ func discoverAndValidateMetrics(archiveBase string, config *Config, logger *Logg
 		}
 	}
 
-	logger.Info(fmt.Sprintf("Found %d total metrics, validating each one...", len(allMetrics)))
+	logger.Info("Found metrics to validate", "total", len(allMetrics))
 
 	// Step 2: Validate metrics in batches
 	batchSize := 100 // Same as Python default
@@ -455,78 +450,45 @@ func discoverAndValidateMetrics(archiveBase string, config *Config, logger *Logg
 
 		// Progress logging
 		if (i+batchSize)%200 == 0 {
-			logger.Info(fmt.Sprintf("Validated %d/%d metrics...", end, len(allMetrics)))
+			logger.Debug("validate", "validation progress", "done", end, "total", len(allMetrics))
 		}
 	}
 
-	logger.Info(fmt.Sprintf("Found %d valid metrics (filtered out %d invalid/derived metrics)", len(validMetrics), invalidCount))
+	logger.Info("Metric validation complete", "valid", len(validMetrics), "invalid", invalidCount)
 
-	// Step 3: Apply category filters (same as Python)
+	// Step 3: Apply metric rules (YAML-configured, or synthesized from the
+	// legacy ENABLE_*_METRICS env vars if no METRIC_RULES_FILE is set).
 	originalCount := len(validMetrics)
 	filteredMetrics := make([]string, 0)
-	filterStats := make(map[string]int)
+	excludedCount := 0
 
-	// Get filter settings from environment or use defaults
-	enableProcess := getEnvBool("ENABLE_PROCESS_METRICS", false)
-	enableDisk := getEnvBool("ENABLE_DISK_METRICS", true)
-	enableFile := getEnvBool("ENABLE_FILE_METRICS", true)
-	enableMemory := getEnvBool("ENABLE_MEMORY_METRICS", true)
-	enableNetwork := getEnvBool("ENABLE_NETWORK_METRICS", true)
-	enableKernel := getEnvBool("ENABLE_KERNEL_METRICS", true)
-	enableSwap := getEnvBool("ENABLE_SWAP_METRICS", true)
+	rules := config.MetricRules
+	if rules == nil {
+		rules = SynthesizeMetricRuleSetFromEnv(config)
+	}
 
 	for _, metric := range validMetrics {
-		// Check category filters
-		if strings.HasPrefix(metric, "proc.") && !enableProcess {
-			filterStats["proc"]++
-			continue
-		}
-		if strings.HasPrefix(metric, "disk.") && !enableDisk {
-			filterStats["disk"]++
-			continue
-		}
-		if (strings.HasPrefix(metric, "vfs.") || strings.HasPrefix(metric, "filesys.")) && !enableFile {
-			filterStats["file"]++
-			continue
-		}
-		if strings.HasPrefix(metric, "mem.") && !enableMemory {
-			filterStats["mem"]++
+		if !rules.Keep(metric) {
+			excludedCount++
 			continue
 		}
-		if strings.HasPrefix(metric, "network.") && !enableNetwork {
-			filterStats["network"]++
-			continue
-		}
-		if strings.HasPrefix(metric, "kernel.") && !enableKernel {
-			filterStats["kernel"]++
-			continue
-		}
-		if strings.HasPrefix(metric, "swap.") && !enableSwap {
-			filterStats["swap"]++
-			continue
-		}
-
 		filteredMetrics = append(filteredMetrics, metric)
 	}
 
-	// Log filter statistics
-	if len(filterStats) > 0 {
-		logger.Info(fmt.Sprintf("Applied category filters: filtered %d metrics", originalCount-len(filteredMetrics)))
-		for category, count := range filterStats {
-			logger.Info(fmt.Sprintf("  - %s: %d metrics filtered", category, count))
-		}
-	}
-
+	logger.Info(fmt.Sprintf("Applied metric rules: excluded %d of %d metrics", excludedCount, originalCount))
 	logger.Info(fmt.Sprintf("Final metric count after filtering: %d", len(filteredMetrics)))
 
 	return filteredMetrics, nil
 }
 
-// Process archive (main processing function)
-func processArchive(archivePath string, config *Config, logger *Logger) error {
+// Process archive (main processing function). extractBaseDir is the
+// directory extractArchive unpacks into; callers that process archives
+// concurrently (see processArchivesParallel) pass a per-worker subdirectory
+// so two in-flight extractions never collide.
+func processArchive(archivePath string, config *Config, logger *Logger, extractBaseDir string) error {
 	archiveName := filepath.Base(archivePath)
 	logger.Separator(fmt.Sprintf("Processing archive: %s", archiveName))
-	logger.Info(fmt.Sprintf("START: Processing %s", archiveName))
+	logger.Info("Processing archive", "archive", archiveName)
 
 	startTime := time.Now()
 	var extractionTime, validationTime, exportTime time.Duration
@@ -534,11 +496,12 @@ func processArchive(archivePath string, config *Config, logger *Logger) error {
 	// Extract archive
 	extractStart := time.Now()
 	logger.Info("Extracting archive...")
-	extractDir, err := extractArchive(archivePath, config.ExtractDir, logger)
+	extractDir, err := extractArchive(archivePath, extractBaseDir, logger)
 	if err != nil {
 		return fmt.Errorf("extraction failed: %w", err)
 	}
 	extractionTime = time.Since(extractStart)
+	logger.Info("Stage complete", "archive", archiveName, "stage", "extract", "duration_ms", extractionTime.Milliseconds())
 
 	// Find PCP archive
 	archiveBase, err := findPCPArchive(extractDir)
@@ -581,24 +544,42 @@ func processArchive(archivePath string, config *Config, logger *Logger) error {
 
 	validationTime = time.Since(validationStart)
 	logger.Info(fmt.Sprintf("Metric validation completed in %.2f seconds", validationTime.Seconds()))
+	logger.Info("Stage complete", "archive", archiveName, "stage", "validate", "duration_ms", validationTime.Milliseconds(), "metric_count", len(validatedMetrics))
 
 	// Export to InfluxDB
 	exportStart := time.Now()
 	logger.Info("Starting InfluxDB export...")
 
-	_, err = exportToInfluxDB(archiveBase, archiveName, validatedMetrics, config, logger)
+	var dataPoints int
+	if config.ArchiveBackend == "native" {
+		source, srcErr := NewArchiveSource(archiveBase, config, logger)
+		if srcErr != nil {
+			return fmt.Errorf("failed to open archive source: %w", srcErr)
+		}
+		defer source.Close()
+
+		dataPoints, err = exportViaArchiveSource(source, archiveName, validatedMetrics, config, logger)
+	} else {
+		dataPoints, err = exportToInfluxDB(archiveBase, archiveName, validatedMetrics, config, logger)
+	}
+	exportTime = time.Since(exportStart)
+	influxdbWriteDuration.Observe(exportTime.Seconds())
 	if err != nil {
+		influxdbWriteErrorsTotal.Inc()
+		archiveProcessDuration.Observe(time.Since(startTime).Seconds())
 		return fmt.Errorf("InfluxDB export failed: %w", err)
 	}
+	metricsWrittenTotal.Add(float64(dataPoints))
 
-	exportTime = time.Since(exportStart)
 	logger.Info(fmt.Sprintf("InfluxDB export completed in %.2f seconds", exportTime.Seconds()))
+	logger.Info("Stage complete", "archive", archiveName, "stage", "export", "duration_ms", exportTime.Milliseconds(), "metric_count", len(validatedMetrics))
 
 	// Summary
 	totalTime := time.Since(startTime)
-	logger.Info(fmt.Sprintf("✓ Successfully exported %s to InfluxDB", archiveName))
+	archiveProcessDuration.Observe(totalTime.Seconds())
+	logger.Info("Successfully exported archive to InfluxDB", "archive", archiveName)
 	logger.Info(fmt.Sprintf("InfluxDB: %s, Org: %s, Bucket: %s", config.InfluxDBURL, config.InfluxDBOrg, config.InfluxDBBucket))
-	logger.Info(fmt.Sprintf("⏱️  TOTAL PROCESSING TIME: %d minutes %.2f seconds", int(totalTime.Minutes()), totalTime.Seconds()-float64(int(totalTime.Minutes())*60)))
+	logger.Info("Processing complete", "archive", archiveName, "stage", "total", "duration_ms", totalTime.Milliseconds(), "metric_count", len(validatedMetrics))
 	logger.Info(fmt.Sprintf("   ├─ Extraction: %.2fs", extractionTime.Seconds()))
 	logger.Info(fmt.Sprintf("   ├─ Validation: %.2fs", validationTime.Seconds()))
 	logger.Info(fmt.Sprintf("   └─ Export: %.2fs", exportTime.Seconds()))
@@ -611,7 +592,7 @@ func processArchive(archivePath string, config *Config, logger *Logger) error {
 		logger.Info(fmt.Sprintf("✓ Moved %s to %s", archiveName, config.ProcessedDir))
 	}
 
-	logger.Info(fmt.Sprintf("COMPLETE: Finished processing %s", archiveName))
+	logger.Info("Finished processing archive", "archive", archiveName)
 
 	// Cleanup extraction directory
 	os.RemoveAll(extractDir)
@@ -619,29 +600,21 @@ func processArchive(archivePath string, config *Config, logger *Logger) error {
 	return nil
 }
 
-// Export to InfluxDB
+// Export to the configured output sink(s)
 func exportToInfluxDB(archiveBase, archiveName string, metrics []string, config *Config, logger *Logger) (int, error) {
-	logger.Separator("STARTING EXPORT TO INFLUXDB")
-	logger.Info("Using Go InfluxDB client")
-	logger.Info(fmt.Sprintf("Value filtering ENABLED: %s", config.PCPMetricsFilter))
-	logger.Info(fmt.Sprintf("Connecting to InfluxDB: %s", config.InfluxDBURL))
-	logger.Info(fmt.Sprintf("Using tags for InfluxDB: product_type=%s, serialNumber=%s", config.ProductType, config.SerialNumber))
-
-	// Create InfluxDB client
-	client := influxdb2.NewClient(config.InfluxDBURL, config.InfluxDBToken)
-	defer client.Close()
-
-	// Get async write API with batching (matches Python behavior)
-	writeAPI := client.WriteAPI(config.InfluxDBOrg, config.InfluxDBBucket)
-	defer writeAPI.Flush()  // Ensure all writes complete before returning
-
-	// Monitor for errors in background
-	errorsCh := writeAPI.Errors()
-	go func() {
-		for err := range errorsCh {
-			logger.Info(fmt.Sprintf("InfluxDB write error: %v", err))
-		}
-	}()
+	logger.Separator("STARTING EXPORT")
+	logger.Info("Output sinks", "sinks", strings.Join(config.OutputSinks, ","))
+	logger.Debug("influx", "value filtering enabled", "filter", config.PCPMetricsFilter)
+
+	// Points are staged per configured sink (see sinks.go); the default
+	// influxdb2 sink stages through a durable per-archive WAL and delivers
+	// with retry/backoff (see wal.go), so a network blip mid-archive no
+	// longer loses points silently.
+	sink, err := BuildSinks(config, archiveName, logger)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build output sinks: %w", err)
+	}
+	defer sink.Close()
 
 	// Execute pmrep command
 	logger.Info(fmt.Sprintf("Extracting metrics using pmrep with %d validated metrics...", len(metrics)))
@@ -657,7 +630,7 @@ func exportToInfluxDB(archiveBase, archiveName string, metrics []string, config
 	args = append(args, metrics...)
 
 	cmd := exec.Command("pmrep", args...)
-	logger.Info(fmt.Sprintf("Command: pmrep -a %s -t 1sec -o csv -U --ignore-unknown [+ %d metrics]", archiveBase, len(metrics)))
+	logger.Debug("pmrep", "running pmrep", "archive_base", archiveBase, "metric_count", len(metrics))
 
 	// Get stdout pipe
 	stdout, err := cmd.StdoutPipe()
@@ -724,18 +697,11 @@ func exportToInfluxDB(archiveBase, archiveName string, metrics []string, config
 			continue
 		}
 
-		// Create point
-		point := influxdb2.NewPoint(
-			config.InfluxDBMeasurement,
-			map[string]string{
-				"product_type": config.ProductType,
-				"serialNumber": config.SerialNumber,
-			},
-			map[string]interface{}{},
-			t,
-		)
-
-		hasFields := false
+		tags := map[string]string{
+			"product_type": config.ProductType,
+			"serialNumber": config.SerialNumber,
+		}
+		fields := make(map[string]interface{})
 
 		// Add all metric values as fields
 		for i := 1; i < len(values); i++ {
@@ -765,20 +731,27 @@ func exportToInfluxDB(archiveBase, archiveName string, metrics []string, config
 				continue
 			}
 
-			// Sanitize field name
-			fieldName := sanitizeFieldName(header[i])
+			// Apply metric rules: rewrite/rename the field and attach any
+			// static or captured-group tags before it's written.
+			fieldName, extraTags, keep := config.MetricRules.Resolve(header[i])
+			if !keep {
+				skippedValues++
+				continue
+			}
+			for k, v := range extraTags {
+				tags[k] = v
+			}
 
-			// Ensure we're adding as float64, not string
-			point.AddField(fieldName, float64(floatVal))
-			hasFields = true
+			fields[fieldName] = float64(floatVal)
 
 			// Track metric
 			saveMetricToCSV(header[i], config.MetricsCSV)
 		}
 
-		if hasFields {
-			// Write point using async API (matches Python batching behavior)
-			writeAPI.WritePoint(point)
+		if len(fields) > 0 {
+			if err := sink.WritePoint(config.InfluxDBMeasurement, tags, fields, t); err != nil {
+				return 0, fmt.Errorf("failed to write point: %w", err)
+			}
 			dataPoints++
 		}
 	}
@@ -791,20 +764,93 @@ func exportToInfluxDB(archiveBase, archiveName string, metrics []string, config
 		logger.Info(fmt.Sprintf("Warning: pmrep exited with error: %v", err))
 	}
 
-	// Flush all pending writes to InfluxDB (matches Python behavior)
-	logger.Info("Flushing async writes to InfluxDB...")
-	writeAPI.Flush()
-	logger.Info("All async writes completed")
+	logger.Info("Flushing sinks...", "archive", archiveName)
+	if err := sink.Flush(); err != nil {
+		return dataPoints, fmt.Errorf("sink flush failed: %w", err)
+	}
 
 	logger.Info(fmt.Sprintf("CSV output saved to: %s", csvPath))
 	logger.Separator("EXPORT COMPLETE")
-	logger.Info(fmt.Sprintf("Total data points written: %d", dataPoints))
+	logger.Info("Total data points written", "count", dataPoints)
 	logger.Info(fmt.Sprintf("Processed %d lines from pmrep", lineCount))
 	logger.Info(fmt.Sprintf("Empty/invalid values skipped: %d", skippedValues))
 
 	return dataPoints, nil
 }
 
+// exportViaArchiveSource streams samples from a pcparchive.ArchiveSource and
+// writes them to the configured sink(s) in-process, without writing a pmrep
+// CSV file. Whether pmrep runs depends on the source: the native reader does
+// not run it, the pmrep-backed fallback does.
+func exportViaArchiveSource(source pcparchive.ArchiveSource, archiveName string, metrics []string, config *Config, logger *Logger) (int, error) {
+	logger.Separator("STARTING EXPORT (native archive reader)")
+	logger.Info("Output sinks", "sinks", strings.Join(config.OutputSinks, ","), "archive", archiveName)
+
+	sink, err := BuildSinks(config, archiveName, logger)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build output sinks: %w", err)
+	}
+	defer sink.Close()
+
+	ctx := context.Background()
+	samples, err := source.Stream(ctx, metrics)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stream archive: %w", err)
+	}
+
+	dataPoints := 0
+	skippedValues := 0
+
+	for sample := range samples {
+		value := strconv.FormatFloat(sample.Value, 'f', -1, 64)
+		if shouldSkipValue(value, config.PCPMetricsFilter) {
+			skippedValues++
+			continue
+		}
+
+		fieldName, extraTags, keep := config.MetricRules.Resolve(sample.Metric)
+		if !keep {
+			skippedValues++
+			continue
+		}
+
+		tags := map[string]string{
+			"product_type": config.ProductType,
+			"serialNumber": config.SerialNumber,
+		}
+		if sample.Instance != "" {
+			tags["instance"] = sample.Instance
+		}
+		for k, v := range extraTags {
+			tags[k] = v
+		}
+
+		if err := sink.WritePoint(config.InfluxDBMeasurement, tags, map[string]interface{}{fieldName: sample.Value}, sample.Timestamp); err != nil {
+			return dataPoints, fmt.Errorf("failed to write point: %w", err)
+		}
+		dataPoints++
+
+		saveMetricToCSV(sample.Metric, config.MetricsCSV)
+	}
+
+	// The channel closing isn't by itself proof the archive was read in
+	// full: Err reports whether Stream gave up early on a decode failure.
+	if err := source.Err(); err != nil {
+		return dataPoints, fmt.Errorf("archive stream ended early: %w", err)
+	}
+
+	logger.Info("Flushing sinks...", "archive", archiveName)
+	if err := sink.Flush(); err != nil {
+		return dataPoints, fmt.Errorf("sink flush failed: %w", err)
+	}
+
+	logger.Separator("EXPORT COMPLETE")
+	logger.Info("Total data points written", "count", dataPoints)
+	logger.Info(fmt.Sprintf("Values skipped by filter: %d", skippedValues))
+
+	return dataPoints, nil
+}
+
 // Sanitize field name (replace dots, dashes, spaces with underscores)
 func sanitizeFieldName(name string) string {
 	name = strings.ReplaceAll(name, ".", "_")
@@ -836,15 +882,45 @@ func shouldSkipValue(value, filter string) bool {
 	return false
 }
 
+// ArchiveResult records the outcome of processing a single archive within a
+// processAllArchives run, for reporting back over the trigger HTTP API.
+type ArchiveResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "success", "failed", "dead_lettered", "retry_scheduled", or "skipped"
+	Error  string `json:"error,omitempty"`
+}
+
+// ProcessSummary is the result of one processAllArchives run.
+type ProcessSummary struct {
+	Successful   int             `json:"successful"`
+	Failed       int             `json:"failed"`
+	DeadLettered int             `json:"dead_lettered"`
+	Archives     []ArchiveResult `json:"archives"`
+}
+
 // Process all archives
-func processAllArchives(config *Config, logger *Logger) error {
+func processAllArchives(config *Config, logger *Logger) (*ProcessSummary, error) {
 	logger.Separator("MANUAL PROCESSING TRIGGERED")
 
+	// Re-check InfluxDB readiness before each run, so a mid-run outage
+	// cleanly defers processing instead of archives being exported against
+	// a dead server and moved to FailedDir for no good reason.
+	if err := WaitForInfluxDBReady(context.Background(), config, logger); err != nil {
+		return nil, fmt.Errorf("InfluxDB not ready, deferring processing: %w", err)
+	}
+
 	// Load configuration from .env
 	if err := config.LoadTagsFromEnv(logger); err != nil {
 		logger.Info(fmt.Sprintf("Warning: failed to load config from .env: %v", err))
 	}
 
+	if config.MetricRules == nil {
+		if err := config.LoadMetricRules(logger); err != nil {
+			logger.Info(fmt.Sprintf("Warning: failed to load metric rules, falling back to env toggles: %v", err))
+			config.MetricRules = SynthesizeMetricRuleSetFromEnv(config)
+		}
+	}
+
 	logger.Separator("DATA TAGGING CONFIGURATION:")
 	logger.Info(fmt.Sprintf("  PRODUCT_TYPE  = %s", config.ProductType))
 	logger.Info(fmt.Sprintf("  SERIAL_NUMBER = %s", config.SerialNumber))
@@ -854,55 +930,204 @@ func processAllArchives(config *Config, logger *Logger) error {
 	if err := loadMetricsCache(config.MetricsCSV); err != nil {
 		logger.Info(fmt.Sprintf("Warning: failed to load metrics cache: %v", err))
 	}
-	logger.Info(fmt.Sprintf("Loaded %d existing metrics from cache", len(metricsCache)))
+	logger.Info(fmt.Sprintf("Loaded %d existing metrics from cache", metricsCacheLen()))
+	metricsCacheSize.Set(float64(metricsCacheLen()))
+
+	// RETRY_FAILED=true pulls retryable archives back from FailedDir before
+	// the scan below, so this run gets another shot at them.
+	rescanFailedForRetry(config, logger)
 
 	// Find archives
 	logger.Info(fmt.Sprintf("Checking for .tar.xz files in %s...", config.WatchDir))
-	matches, err := filepath.Glob(filepath.Join(config.WatchDir, "*.tar.xz"))
+	allMatches, err := filepath.Glob(filepath.Join(config.WatchDir, "*.tar.xz"))
 	if err != nil {
-		return fmt.Errorf("failed to find archives: %w", err)
+		return nil, fmt.Errorf("failed to find archives: %w", err)
+	}
+
+	// Archives still serving out their retry backoff are skipped this cycle
+	// rather than retried immediately, so a transient failure doesn't just
+	// get hammered again on the very next trigger.
+	var matches []string
+	for _, path := range allMatches {
+		if isInBackoff(path, config) {
+			logger.Info(fmt.Sprintf("Skipping %s: still in retry backoff", filepath.Base(path)))
+			continue
+		}
+		matches = append(matches, path)
 	}
 
+	archivesPending.Set(float64(len(matches)))
+
+	summary := &ProcessSummary{}
+
 	if len(matches) == 0 {
 		logger.Info("No files found to process")
-		return nil
+		lastRunTimestamp.SetToCurrentTime()
+		return summary, nil
 	}
 
 	logger.Info(fmt.Sprintf("Found %d archive(s) to process", len(matches)))
 
-	// Process each archive
-	successCount := 0
-	failedCount := 0
+	for _, result := range processArchivesParallel(matches, config, logger) {
+		switch result.Status {
+		case "success":
+			summary.Successful++
+		case "skipped":
+			// Claimed by the ingestion service at the same moment; it
+			// counts in neither bucket, the other consumer owns the result.
+		case "dead_lettered":
+			// Some points never reached InfluxDB; the archive is in
+			// FailedDir, not ProcessedDir, so don't count it as a success.
+			summary.DeadLettered++
+		default: // "failed" or "retry_scheduled"
+			summary.Failed++
+		}
+		summary.Archives = append(summary.Archives, result)
+	}
 
-	for _, archivePath := range matches {
-		archiveName := filepath.Base(archivePath)
-		logger.Info(fmt.Sprintf("Processing: %s", archiveName))
+	archivesPending.Set(0)
+	lastRunTimestamp.SetToCurrentTime()
+	logger.Separator(fmt.Sprintf("PROCESSING COMPLETE: %d successful, %d failed, %d dead-lettered", summary.Successful, summary.Failed, summary.DeadLettered))
+	return summary, nil
+}
 
-		if err := processArchive(archivePath, config, logger); err != nil {
-			logger.Info(fmt.Sprintf("✗ Failed to process %s: %v", archiveName, err))
+// processArchivesParallel runs processArchive over matches using a worker
+// pool sized by PROCESS_WORKERS (default runtime.NumCPU()). Each worker gets
+// its own extraction subdirectory so concurrent extractions never collide,
+// and each archive gets its own timeout (PROCESS_ARCHIVE_TIMEOUT_SECONDS,
+// default 1800s) so one stuck archive can't wedge the whole run. Results
+// come back in archive-path order, not completion order, so the summary
+// reads the same regardless of which worker happened to finish first.
+func processArchivesParallel(matches []string, config *Config, logger *Logger) []ArchiveResult {
+	workers := getEnvInt("PROCESS_WORKERS", runtime.NumCPU())
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(matches) {
+		workers = len(matches)
+	}
+	timeout := time.Duration(getEnvInt("PROCESS_ARCHIVE_TIMEOUT_SECONDS", 1800)) * time.Second
 
-			// Move to failed directory
-			failedPath := filepath.Join(config.FailedDir, archiveName)
-			if moveErr := os.Rename(archivePath, failedPath); moveErr != nil {
-				logger.Info(fmt.Sprintf("Warning: failed to move archive to failed: %v", moveErr))
-			} else {
-				logger.Info(fmt.Sprintf("✗ Moved %s to %s", archiveName, config.FailedDir))
-			}
+	type job struct {
+		index int
+		path  string
+	}
+	jobs := make(chan job)
+	results := make([]ArchiveResult, len(matches))
 
-			failedCount++
-		} else {
-			successCount++
+	var successCount, failedCount int64
+	var done int64
+	total := int64(len(matches))
+
+	progressDone := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				logger.Info(fmt.Sprintf("Progress: %d/%d archives done (%d ok, %d failed)",
+					atomic.LoadInt64(&done), total, atomic.LoadInt64(&successCount), atomic.LoadInt64(&failedCount)))
+			case <-progressDone:
+				return
+			}
 		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		workerID := w
+		go func() {
+			defer wg.Done()
+			extractDir := filepath.Join(config.ExtractDir, fmt.Sprintf("worker-%d", workerID))
+
+			for j := range jobs {
+				archiveName := filepath.Base(j.path)
+
+				// The ingestion service watches the same WatchDir, so claim
+				// the archive before touching it: if ingestion got there
+				// first, skip rather than double-extract/double-export it.
+				if !claimArchive(j.path) {
+					logger.Info(fmt.Sprintf("[worker %d] %s already claimed elsewhere, skipping", workerID, archiveName))
+					results[j.index] = ArchiveResult{Name: archiveName, Status: "skipped"}
+					atomic.AddInt64(&done, 1)
+					continue
+				}
+
+				logger.Info(fmt.Sprintf("[worker %d] Processing: %s", workerID, archiveName))
+
+				err := runProcessArchiveWithTimeout(j.path, config, logger, extractDir, timeout)
+				releaseArchive(j.path)
+				if err != nil {
+					logger.Info(fmt.Sprintf("✗ Failed to process %s: %v", archiveName, err))
+
+					if recordFailureAndDecide(j.path, config, logger, err) {
+						quarantineArchive(j.path, config.FailedDir, err, logger)
+						status := "failed"
+						var dlErr *DeadLetterError
+						if errors.As(err, &dlErr) {
+							status = "dead_lettered"
+						}
+						results[j.index] = ArchiveResult{Name: archiveName, Status: status, Error: err.Error()}
+						archivesProcessedTotal.WithLabelValues(status).Inc()
+					} else {
+						results[j.index] = ArchiveResult{Name: archiveName, Status: "retry_scheduled", Error: err.Error()}
+						archivesProcessedTotal.WithLabelValues("retry_scheduled").Inc()
+					}
+					atomic.AddInt64(&failedCount, 1)
+				} else {
+					clearAttemptRecord(j.path, config)
+					results[j.index] = ArchiveResult{Name: archiveName, Status: "success"}
+					archivesProcessedTotal.WithLabelValues("success").Inc()
+					atomic.AddInt64(&successCount, 1)
+				}
+				atomic.AddInt64(&done, 1)
+			}
+		}()
 	}
 
-	logger.Separator(fmt.Sprintf("PROCESSING COMPLETE: %d successful, %d failed", successCount, failedCount))
-	return nil
+	for i, path := range matches {
+		jobs <- job{index: i, path: path}
+	}
+	close(jobs)
+
+	wg.Wait()
+	close(progressDone)
+
+	return results
+}
+
+// runProcessArchiveWithTimeout bounds a single processArchive call: if it
+// doesn't finish within timeout the run moves on and reports a failure, but
+// the underlying goroutine is left to finish on its own (processArchive has
+// no cancellation points to abort early).
+func runProcessArchiveWithTimeout(archivePath string, config *Config, logger *Logger, extractDir string, timeout time.Duration) error {
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- processArchive(archivePath, config, logger, extractDir)
+	}()
+
+	select {
+	case err := <-doneCh:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
 }
 
 func main() {
 	// Load configuration
 	config := LoadConfig()
 
+	// `pcp-parser replay <wal-or-deadletter-file>` re-sends a previously
+	// staged line-protocol file to InfluxDB, for recovering after an outage
+	// without re-parsing the archive it came from.
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplayCommand(config, os.Args[2:])
+		return
+	}
+
 	// Create necessary directories
 	os.MkdirAll(config.WatchDir, 0755)
 	os.MkdirAll(config.ProcessedDir, 0755)
@@ -922,6 +1147,11 @@ func main() {
 		logger.Info(fmt.Sprintf("Warning: failed to load tags from .env: %v", err))
 	}
 
+	if err := config.LoadMetricRules(logger); err != nil {
+		logger.Info(fmt.Sprintf("Warning: failed to load metric rules, falling back to env toggles: %v", err))
+		config.MetricRules = SynthesizeMetricRuleSetFromEnv(config)
+	}
+
 	// Print startup info
 	logger.Separator("PCP Archive to InfluxDB Processor (Go)")
 	logger.Info(fmt.Sprintf("Watch directory: %s", config.WatchDir))
@@ -931,6 +1161,8 @@ func main() {
 	logger.Info(fmt.Sprintf("Log directory: %s", config.LogDir))
 	logger.Info(fmt.Sprintf("InfluxDB URL: %s", config.InfluxDBURL))
 	logger.Info(fmt.Sprintf("InfluxDB Measurement: %s", config.InfluxDBMeasurement))
+	logger.Info(fmt.Sprintf("Archive backend: %s", config.ArchiveBackend))
+	logger.Info(fmt.Sprintf("Output sinks: %s", strings.Join(config.OutputSinks, ",")))
 	logger.Info(fmt.Sprintf("Static Tags - Product Type: %s, Serial Number: %s", config.ProductType, config.SerialNumber))
 	logger.Info("")
 
@@ -938,41 +1170,41 @@ func main() {
 	if err := loadMetricsCache(config.MetricsCSV); err != nil {
 		logger.Info(fmt.Sprintf("Warning: failed to load metrics cache: %v", err))
 	}
-	logger.Info(fmt.Sprintf("Loaded %d existing metrics from cache", len(metricsCache)))
+	logger.Info(fmt.Sprintf("Loaded %d existing metrics from cache", metricsCacheLen()))
 
 	// Wait for InfluxDB to be ready
 	logger.Info("Waiting for InfluxDB to be ready...")
-	for {
-		// TODO: Implement health check
-		logger.Info("InfluxDB is ready!")
-		break
+	if err := WaitForInfluxDBReady(context.Background(), config, logger); err != nil {
+		logger.Critical("InfluxDB readiness check failed, refusing to start", "error", err.Error())
+		log.Fatalf("InfluxDB readiness check failed: %v", err)
 	}
 
+	triggerServer := NewTriggerServer(config, logger)
+
 	logger.Info("")
-	logger.Info("Waiting for manual trigger via web interface...")
-	logger.Info("Trigger file: /src/.process_trigger_go")
+	logger.Info("Watching for new archives and waiting for manual trigger via HTTP or SIGHUP...")
+	logger.Info(fmt.Sprintf("Trigger endpoint: POST http://%s/trigger (status: GET /status)", getEnv("TRIGGER_LISTEN", "127.0.0.1:8765")))
 	logger.Info("")
 
-	// Main monitoring loop
-	triggerFile := "/src/.process_trigger_go"
+	ctx, cancel := context.WithCancel(context.Background())
+	go waitForShutdownSignal(cancel, logger)
+	go triggerServer.watchSighup(ctx)
 
-	for {
-		// Check if trigger file exists
-		if _, err := os.Stat(triggerFile); err == nil {
-			logger.Info("TRIGGER DETECTED - Starting processing...")
-
-			// Remove trigger file immediately (matches Python behavior)
-			os.Remove(triggerFile)
-
-			// Process all archives
-			if err := processAllArchives(config, logger); err != nil {
-				logger.Info(fmt.Sprintf("Error during processing: %v", err))
-			}
-
-			logger.Info("Waiting for next trigger...")
-		}
+	// The ingestion service auto-detects archives landing in WatchDir and
+	// processes them concurrently; it runs until the shutdown signal cancels ctx.
+	ingestionDone := make(chan struct{})
+	go func() {
+		RunIngestionService(ctx, config, logger)
+		close(ingestionDone)
+	}()
 
-		// Sleep for 2 seconds
-		time.Sleep(2 * time.Second)
+	// The trigger server blocks until ctx is cancelled, at which point it
+	// shuts itself down gracefully (waiting up to 10s for an in-flight
+	// /trigger request to finish).
+	if err := triggerServer.Start(ctx); err != nil {
+		logger.Info(fmt.Sprintf("Trigger server error: %v", err))
 	}
+
+	<-ingestionDone
+	logger.Info("Shutdown complete")
 }
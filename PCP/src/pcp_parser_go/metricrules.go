@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetricRuleAction is what a rule does once its pattern matches a metric name.
+type MetricRuleAction string
+
+const (
+	RuleActionInclude MetricRuleAction = "include"
+	RuleActionExclude MetricRuleAction = "exclude"
+	RuleActionRewrite MetricRuleAction = "rewrite"
+)
+
+// MetricRule is one entry of METRIC_RULES_FILE. Rules are evaluated in
+// order and the first one whose Match regex matches wins, mirroring the
+// config-as-YAML approach used by tools like k6/snmptrapd: ordered,
+// first-match rule lists rather than a flat set of booleans.
+type MetricRule struct {
+	Match  string            `yaml:"match"`
+	Action MetricRuleAction  `yaml:"action"`
+	Rename string            `yaml:"rename,omitempty"`
+	Tags   map[string]string `yaml:"tags,omitempty"`
+	Type   string            `yaml:"type,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// MetricRuleSet is an ordered list of compiled MetricRules.
+type MetricRuleSet struct {
+	Rules []*MetricRule
+}
+
+// metricRulesFile is the on-disk shape of METRIC_RULES_FILE.
+type metricRulesFile struct {
+	Rules []*MetricRule `yaml:"rules"`
+}
+
+// LoadMetricRuleSet reads and compiles the YAML rules file at path.
+func LoadMetricRuleSet(path string) (*MetricRuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metric rules file: %w", err)
+	}
+
+	var parsed metricRulesFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse metric rules file: %w", err)
+	}
+
+	for i, rule := range parsed.Rules {
+		if rule.Action == "" {
+			rule.Action = RuleActionInclude
+		}
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: invalid match regex %q: %w", i, rule.Match, err)
+		}
+		rule.re = re
+	}
+
+	return &MetricRuleSet{Rules: parsed.Rules}, nil
+}
+
+// SynthesizeMetricRuleSetFromEnv builds an equivalent rule set from the
+// legacy ENABLE_*_METRICS booleans, so existing deployments keep working
+// unchanged when METRIC_RULES_FILE is not set.
+func SynthesizeMetricRuleSetFromEnv(config *Config) *MetricRuleSet {
+	type toggle struct {
+		prefix  string
+		enabled bool
+	}
+	toggles := []toggle{
+		{`^proc\.`, config.EnableProcessMetrics},
+		{`^disk\.`, config.EnableDiskMetrics},
+		{`^(vfs|filesys)\.`, config.EnableFileMetrics},
+		{`^mem\.`, config.EnableMemoryMetrics},
+		{`^network\.`, config.EnableNetworkMetrics},
+		{`^kernel\.`, config.EnableKernelMetrics},
+		{`^swap\.`, config.EnableSwapMetrics},
+		{`^nfs\.`, config.EnableNFSMetrics},
+	}
+
+	var rules []*MetricRule
+	for _, t := range toggles {
+		if t.enabled {
+			continue
+		}
+		rules = append(rules, &MetricRule{
+			Match:  t.prefix,
+			Action: RuleActionExclude,
+			re:     regexp.MustCompile(t.prefix),
+		})
+	}
+	// Default: anything not explicitly excluded above is kept as-is.
+	rules = append(rules, &MetricRule{
+		Match:  ".*",
+		Action: RuleActionInclude,
+		re:     regexp.MustCompile(".*"),
+	})
+
+	return &MetricRuleSet{Rules: rules}
+}
+
+// match returns the first rule whose pattern matches metric, and the
+// capture groups from that match (for $1-style tag/rename substitution).
+func (rs *MetricRuleSet) match(metric string) (*MetricRule, []string) {
+	for _, rule := range rs.Rules {
+		if loc := rule.re.FindStringSubmatch(metric); loc != nil {
+			return rule, loc
+		}
+	}
+	return nil, nil
+}
+
+// Keep reports whether metric survives pre-filtering (used by
+// discoverAndValidateMetrics). Metrics with no matching rule are kept.
+func (rs *MetricRuleSet) Keep(metric string) bool {
+	rule, _ := rs.match(metric)
+	if rule == nil {
+		return true
+	}
+	return rule.Action != RuleActionExclude
+}
+
+// Resolve applies include/exclude/rewrite to metric, returning the field
+// name to write to InfluxDB (after `rename:` substitution), any static or
+// captured-group tags to attach, and whether the metric should be written
+// at all. It is called just before the field is added to the point written
+// to the output sink(s).
+func (rs *MetricRuleSet) Resolve(metric string) (field string, tags map[string]string, keep bool) {
+	rule, groups := rs.match(metric)
+	if rule == nil || rule.Action == RuleActionInclude {
+		return sanitizeFieldName(metric), nil, true
+	}
+	if rule.Action == RuleActionExclude {
+		return "", nil, false
+	}
+
+	// RuleActionRewrite
+	field = metric
+	if rule.Rename != "" {
+		field = expandCaptureGroups(rule.Rename, groups)
+	}
+
+	var resolvedTags map[string]string
+	if len(rule.Tags) > 0 {
+		resolvedTags = make(map[string]string, len(rule.Tags))
+		for k, v := range rule.Tags {
+			resolvedTags[k] = expandCaptureGroups(v, groups)
+		}
+	}
+
+	return sanitizeFieldName(field), resolvedTags, true
+}
+
+// expandCaptureGroups replaces $1, $2, ... in template with the
+// corresponding regex capture group from groups (groups[0] is the full match).
+func expandCaptureGroups(template string, groups []string) string {
+	if len(groups) == 0 {
+		return template
+	}
+	result := template
+	for i := len(groups) - 1; i >= 1; i-- {
+		result = strings.ReplaceAll(result, fmt.Sprintf("$%d", i), groups[i])
+	}
+	return result
+}
+
+// LoadMetricRules populates config.MetricRules from METRIC_RULES_FILE, or
+// falls back to a rule set synthesized from the legacy ENABLE_*_METRICS
+// env vars if no file is configured.
+func (c *Config) LoadMetricRules(logger *Logger) error {
+	if c.MetricRulesFile == "" {
+		c.MetricRules = SynthesizeMetricRuleSetFromEnv(c)
+		logger.Info("No METRIC_RULES_FILE set, using rules synthesized from ENABLE_*_METRICS env vars")
+		return nil
+	}
+
+	rules, err := LoadMetricRuleSet(c.MetricRulesFile)
+	if err != nil {
+		return err
+	}
+	c.MetricRules = rules
+	logger.Info(fmt.Sprintf("Loaded %d metric rule(s) from %s", len(rules.Rules), c.MetricRulesFile))
+	return nil
+}
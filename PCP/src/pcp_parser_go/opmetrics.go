@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Operational metrics for the parser itself, exposed on /metrics by
+// TriggerServer (see triggerserver.go) for Grafana alongside the PCP data
+// being ingested. Kept in one file so the full self-observability surface
+// is visible at a glance instead of scattered register calls.
+var (
+	archivesProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pcp_archives_processed_total",
+		Help: "Archives processed, labeled by outcome (success, failed, dead_lettered, retry_scheduled).",
+	}, []string{"status"})
+
+	metricsWrittenTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pcp_metrics_written_total",
+		Help: "Total metric points written to the configured output sink(s).",
+	})
+
+	influxdbWriteErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pcp_influxdb_write_errors_total",
+		Help: "Total failed export attempts to the configured output sink(s).",
+	})
+
+	archiveProcessDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pcp_archive_process_duration_seconds",
+		Help:    "End-to-end time (extract+validate+export) to process one archive.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	influxdbWriteDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pcp_influxdb_write_duration_seconds",
+		Help:    "Time spent exporting one archive's points to the configured sink(s).",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	archivesPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pcp_archives_pending",
+		Help: "Archives currently waiting in WatchDir for the next processing run.",
+	})
+
+	metricsCacheSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pcp_metrics_cache_size",
+		Help: "Number of metrics currently tracked in the metrics cache.",
+	})
+
+	lastRunTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pcp_last_run_timestamp_seconds",
+		Help: "Unix timestamp of the last completed processAllArchives run.",
+	})
+)
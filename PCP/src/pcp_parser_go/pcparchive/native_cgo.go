@@ -0,0 +1,217 @@
+//go:build pcp_cgo
+
+package pcparchive
+
+/*
+#cgo LDFLAGS: -lpcp
+#include <pcp/pmapi.h>
+#include <stdlib.h>
+
+extern void pcparchiveGoPMNSCallback(char *name);
+
+static int pcparchive_traverse(const char *name) {
+	return pmTraversePMNS(name, pcparchiveGoPMNSCallback);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// traverseNames/traverseMu back the pmTraversePMNS callback below: libpcp
+// invokes it once per metric name found, and since it's a plain C function
+// pointer (no userdata parameter) there is nowhere else to stash the result.
+var (
+	traverseMu    sync.Mutex
+	traverseNames []string
+)
+
+//export pcparchiveGoPMNSCallback
+func pcparchiveGoPMNSCallback(cName *C.char) {
+	traverseMu.Lock()
+	traverseNames = append(traverseNames, C.GoString(cName))
+	traverseMu.Unlock()
+}
+
+// nativeSource opens the archive directly via libpcp and decodes samples
+// with pmFetchArchive/pmExtractValue, avoiding the per-batch pminfo/pmrep
+// fork that the pmrep fallback relies on.
+type nativeSource struct {
+	ctxID C.int
+
+	mu  sync.Mutex
+	err error
+}
+
+// setErr records the cause of an early Stream exit, for Err() to return.
+func (s *nativeSource) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// Err returns the error (if any) that ended the most recent Stream early.
+func (s *nativeSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// NewNativeSource opens archiveBase (the path with the .meta/.0/.index
+// suffixes stripped) as a PM_CONTEXT_ARCHIVE context.
+func NewNativeSource(archiveBase string) (ArchiveSource, error) {
+	cPath := C.CString(archiveBase)
+	defer C.free(unsafe.Pointer(cPath))
+
+	ctxID := C.pmNewContext(C.PM_CONTEXT_ARCHIVE, cPath)
+	if ctxID < 0 {
+		return nil, fmt.Errorf("pmNewContext(%s): %s", archiveBase, C.GoString(C.pmErrStr(ctxID)))
+	}
+
+	return &nativeSource{ctxID: ctxID}, nil
+}
+
+// Metrics walks the archive's PMNS and returns every metric name it contains.
+func (s *nativeSource) Metrics() ([]string, error) {
+	if rc, err := C.pmUseContext(s.ctxID); rc < 0 {
+		_ = err
+		return nil, fmt.Errorf("pmUseContext: %s", C.GoString(C.pmErrStr(rc)))
+	}
+
+	traverseMu.Lock()
+	traverseNames = traverseNames[:0]
+	traverseMu.Unlock()
+
+	cRoot := C.CString("")
+	defer C.free(unsafe.Pointer(cRoot))
+
+	if rc := C.pcparchive_traverse(cRoot); rc < 0 {
+		return nil, fmt.Errorf("pmTraversePMNS: %s", C.GoString(C.pmErrStr(rc)))
+	}
+
+	traverseMu.Lock()
+	names := make([]string, len(traverseNames))
+	copy(names, traverseNames)
+	traverseMu.Unlock()
+
+	return names, nil
+}
+
+// Stream decodes samples for the requested metrics in timestamp order,
+// resolving PMIDs up front and then walking the archive record-by-record
+// with pmFetchArchive until it runs out of data or ctx is cancelled.
+func (s *nativeSource) Stream(ctx context.Context, metrics []string) (<-chan Sample, error) {
+	if rc, err := C.pmUseContext(s.ctxID); rc < 0 {
+		_ = err
+		return nil, fmt.Errorf("pmUseContext: %s", C.GoString(C.pmErrStr(rc)))
+	}
+
+	pmids := make([]C.pmID, len(metrics))
+	cNames := make([]*C.char, len(metrics))
+	for i, name := range metrics {
+		cNames[i] = C.CString(name)
+	}
+	defer func() {
+		for _, cn := range cNames {
+			C.free(unsafe.Pointer(cn))
+		}
+	}()
+
+	if rc := C.pmLookupName(C.int(len(metrics)), &cNames[0], &pmids[0]); rc < 0 {
+		return nil, fmt.Errorf("pmLookupName: %s", C.GoString(C.pmErrStr(rc)))
+	}
+
+	// Resolve each metric's instance domain and stored type up front: the
+	// indom so samples can carry a real instance name instead of the raw
+	// numeric inst from pmValue, and the type so pmExtractValue knows what
+	// it's converting from.
+	indoms := make([]C.pmInDom, len(metrics))
+	itypes := make([]C.int, len(metrics))
+	for i, pmid := range pmids {
+		var desc C.pmDesc
+		if rc := C.pmLookupDesc(pmid, &desc); rc < 0 {
+			return nil, fmt.Errorf("pmLookupDesc(%s): %s", metrics[i], C.GoString(C.pmErrStr(rc)))
+		}
+		indoms[i] = desc.indom
+		itypes[i] = C.int(desc.type_)
+	}
+
+	s.setErr(nil)
+	ch := make(chan Sample, 4096)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			var result *C.pmResult
+			rc := C.pmFetchArchive(&result)
+			if rc < 0 {
+				// PM_ERR_EOL signals a clean end of archive; anything else is
+				// a genuine decode failure, which Err() surfaces to the
+				// caller so it isn't mistaken for a fully-read archive.
+				if rc != C.PM_ERR_EOL {
+					s.setErr(fmt.Errorf("pmFetchArchive: %s", C.GoString(C.pmErrStr(rc))))
+				}
+				return
+			}
+
+			ts := time.Unix(int64(result.timestamp.tv_sec), int64(result.timestamp.tv_usec)*1000)
+
+			numpmid := int(result.numpmid)
+			vsetPtr := (*[1 << 20]*C.pmValueSet)(unsafe.Pointer(result.vset))[:numpmid:numpmid]
+
+			for i, vset := range vsetPtr {
+				if int(vset.numval) <= 0 {
+					continue
+				}
+				metricName := metrics[i]
+
+				for j := 0; j < int(vset.numval); j++ {
+					valPtr := (*[1 << 20]C.pmValue)(unsafe.Pointer(&vset.vlist[0]))[j]
+					var atom C.pmAtomValue
+					C.pmExtractValue(vset.valfmt, &valPtr, itypes[i], &atom, C.PM_TYPE_DOUBLE)
+					dval := atom.d
+
+					instance := ""
+					if indoms[i] != C.PM_INDOM_NULL {
+						var cInstName *C.char
+						if rc := C.pmNameInDom(indoms[i], valPtr.inst, &cInstName); rc >= 0 {
+							instance = C.GoString(cInstName)
+							C.free(unsafe.Pointer(cInstName))
+						} else {
+							instance = fmt.Sprintf("%d", valPtr.inst)
+						}
+					}
+
+					select {
+					case ch <- Sample{Timestamp: ts, Metric: metricName, Instance: instance, Value: float64(dval)}:
+					case <-ctx.Done():
+						C.pmFreeResult(result)
+						return
+					}
+				}
+			}
+
+			C.pmFreeResult(result)
+		}
+	}()
+
+	return ch, nil
+}
+
+// Close releases the PCP archive context.
+func (s *nativeSource) Close() error {
+	C.pmDestroyContext(s.ctxID)
+	return nil
+}
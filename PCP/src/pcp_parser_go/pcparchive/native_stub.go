@@ -0,0 +1,12 @@
+//go:build !pcp_cgo
+
+package pcparchive
+
+import "fmt"
+
+// NewNativeSource is only available when built with `-tags pcp_cgo` against
+// a host that has libpcp-dev installed. Without that tag, NewArchiveSource
+// falls back to the pmrep-backed source automatically.
+func NewNativeSource(archiveBase string) (ArchiveSource, error) {
+	return nil, fmt.Errorf("native pcparchive backend not compiled in: rebuild with -tags pcp_cgo")
+}
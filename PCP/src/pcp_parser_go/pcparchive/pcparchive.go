@@ -0,0 +1,42 @@
+// Package pcparchive reads PCP archives (the .meta/.0/.index triplet
+// produced by pmlogger) without shelling out to pminfo/pmrep for every
+// batch. It is used behind the ArchiveSource interface so processArchive
+// can swap between the native reader and the pmrep-based fallback with a
+// single env var.
+package pcparchive
+
+import (
+	"context"
+	"time"
+)
+
+// Sample is a single (timestamp, metric, instance, value) tuple decoded
+// from an archive.
+type Sample struct {
+	Timestamp time.Time
+	Metric    string
+	Instance  string
+	Value     float64
+}
+
+// ArchiveSource abstracts reading metric names and streaming samples out of
+// a PCP archive, so callers don't need to care whether the data came from
+// libpcp directly or from a shelled-out pmrep process.
+type ArchiveSource interface {
+	// Metrics returns every metric name present in the archive.
+	Metrics() ([]string, error)
+
+	// Stream decodes samples for the given metrics and sends them on the
+	// returned channel in timestamp order. The channel is closed when the
+	// archive is exhausted, ctx is cancelled, or an unrecoverable error
+	// occurs (in which case Err returns the cause).
+	Stream(ctx context.Context, metrics []string) (<-chan Sample, error)
+
+	// Err returns the error that caused the Stream channel to close early,
+	// or nil if the archive was exhausted (or Stream hasn't been called, or
+	// is still running). Callers should check it once the channel drains.
+	Err() error
+
+	// Close releases any archive context/handles held by the source.
+	Close() error
+}
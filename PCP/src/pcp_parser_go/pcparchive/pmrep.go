@@ -0,0 +1,151 @@
+package pcparchive
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PmrepSource implements ArchiveSource by shelling out to pminfo/pmrep, the
+// same tools the rest of this codebase used before the native reader
+// existed. It is the fallback used when the native reader cannot be opened,
+// e.g. on a binary built without the pcp_cgo tag or a host without
+// libpcp-dev.
+type PmrepSource struct {
+	archiveBase string
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewPmrepSource returns a pmrep-backed ArchiveSource for archiveBase (the
+// path with the .meta/.0/.index suffixes stripped).
+func NewPmrepSource(archiveBase string) (ArchiveSource, error) {
+	return &PmrepSource{archiveBase: archiveBase}, nil
+}
+
+// Metrics shells out to `pminfo -a` and returns every metric name it prints.
+func (s *PmrepSource) Metrics() ([]string, error) {
+	cmd := exec.Command("pminfo", "-a", s.archiveBase)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("pminfo failed: %w, output: %s", err, string(output))
+	}
+
+	var metrics []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			metrics = append(metrics, name)
+		}
+	}
+	return metrics, nil
+}
+
+// Stream runs `pmrep -a <archive> -t 1sec -o csv` for the given metrics and
+// decodes each CSV row into one Sample per non-empty field.
+func (s *PmrepSource) Stream(ctx context.Context, metrics []string) (<-chan Sample, error) {
+	args := append([]string{"-a", s.archiveBase, "-t", "1sec", "-o", "csv", "-U", "--ignore-unknown"}, metrics...)
+	cmd := exec.CommandContext(ctx, "pmrep", args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start pmrep: %w", err)
+	}
+
+	s.mu.Lock()
+	s.err = nil
+	s.mu.Unlock()
+
+	ch := make(chan Sample, 1024)
+
+	go func() {
+		defer close(ch)
+		defer func() {
+			if err := cmd.Wait(); err != nil {
+				s.mu.Lock()
+				if s.err == nil {
+					s.err = fmt.Errorf("pmrep exited with error: %w", err)
+				}
+				s.mu.Unlock()
+			}
+		}()
+
+		scanner := bufio.NewScanner(stdout)
+		const maxCapacity = 10 * 1024 * 1024
+		buf := make([]byte, maxCapacity)
+		scanner.Buffer(buf, maxCapacity)
+
+		var header []string
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if header == nil {
+				raw := strings.Split(line, ",")
+				header = make([]string, len(raw))
+				for i, col := range raw {
+					header[i] = strings.Trim(strings.TrimSpace(col), `"`)
+				}
+				continue
+			}
+
+			values := strings.Split(line, ",")
+			if len(values) != len(header) {
+				continue
+			}
+
+			ts, err := time.Parse("2006-01-02 15:04:05", values[0])
+			if err != nil {
+				continue
+			}
+
+			for i := 1; i < len(values); i++ {
+				value := strings.Trim(strings.TrimSpace(values[i]), `"`)
+				if value == "" || value == "N/A" || value == "null" || value == "none" {
+					continue
+				}
+				floatVal, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					continue
+				}
+
+				select {
+				case ch <- Sample{Timestamp: ts, Metric: header[i], Value: floatVal}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = fmt.Errorf("reading pmrep output: %w", err)
+			}
+			s.mu.Unlock()
+		}
+	}()
+
+	return ch, nil
+}
+
+// Err returns the error that caused the Stream channel to close early (a
+// non-zero pmrep exit or a failure reading its output), or nil.
+func (s *PmrepSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close is a no-op for the pmrep-backed source: there is no persistent
+// archive handle to release, only per-call subprocesses.
+func (s *PmrepSource) Close() error {
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrorClass says whether a processArchive failure is worth retrying, or
+// whether the archive itself is the problem and retrying would just burn
+// the same failure again.
+type ErrorClass string
+
+const (
+	ErrorRetryable    ErrorClass = "retryable"
+	ErrorNonRetryable ErrorClass = "non_retryable"
+)
+
+// classifyError inspects a processArchive error and buckets it. Transient
+// InfluxDB/network failures (5xx, connection refused/reset, timeouts) are
+// retryable; archive-shape problems (corrupt archive, no PCP archive found,
+// no valid metrics) are not. Anything unrecognized defaults to retryable,
+// since treating an unknown failure as permanent risks quarantining archives
+// that would have succeeded on the next attempt.
+func classifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorRetryable
+	}
+
+	// A WAL dead-letter is never worth retrying: the points that could be
+	// delivered already were, and retrying would re-parse and re-export the
+	// whole archive, re-delivering them. Recovery is via `pcp-parser replay`.
+	var dlErr *DeadLetterError
+	if errors.As(err, &dlErr) {
+		return ErrorNonRetryable
+	}
+
+	msg := strings.ToLower(err.Error())
+
+	nonRetryableMarkers := []string{
+		"failed to find pcp archive",
+		"no valid metrics found",
+		"failed to discover metrics",
+		"extraction failed",
+		"corrupt",
+		"no such pcp archive",
+	}
+	for _, m := range nonRetryableMarkers {
+		if strings.Contains(msg, m) {
+			return ErrorNonRetryable
+		}
+	}
+
+	return ErrorRetryable
+}
+
+// AttemptRecord is the `<archive>.attempts.json` sidecar tracking an
+// archive's retry history, written alongside the archive in WatchDir.
+type AttemptRecord struct {
+	Archive     string    `json:"archive"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	LastClass   string    `json:"last_error_class"`
+	LastAttempt time.Time `json:"last_attempt"`
+	NextRetryAt time.Time `json:"next_retry_at,omitempty"`
+}
+
+// attemptsSidecarPath returns the sidecar path for archiveName in dir.
+func attemptsSidecarPath(dir, archiveName string) string {
+	return filepath.Join(dir, archiveName+".attempts.json")
+}
+
+// loadAttemptRecord reads a sidecar if present, returning a fresh zero-value
+// record (not an error) if it doesn't exist yet.
+func loadAttemptRecord(path, archiveName string) (*AttemptRecord, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &AttemptRecord{Archive: archiveName}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec AttemptRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt attempts sidecar %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+func saveAttemptRecord(path string, rec *AttemptRecord) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// retryBackoff computes the delay before attempt number `attempt` (1-based)
+// is allowed to run again, doubling from RETRY_BACKOFF_SECONDS (default 30)
+// and capping at RETRY_BACKOFF_MAX_SECONDS (default 600).
+func retryBackoff(attempt int) time.Duration {
+	base := time.Duration(getEnvInt("RETRY_BACKOFF_SECONDS", 30)) * time.Second
+	maxBackoff := time.Duration(getEnvInt("RETRY_BACKOFF_MAX_SECONDS", 600)) * time.Second
+
+	backoff := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// recordFailureAndDecide updates the attempts sidecar for archivePath after a
+// processArchive failure and decides whether the archive should be
+// quarantined to FailedDir now. It returns true ("quarantine it") once the
+// error is classified non-retryable or MAX_RETRIES (default 3) is reached;
+// otherwise it stamps NextRetryAt and leaves the archive in WatchDir so a
+// later trigger cycle picks it back up.
+func recordFailureAndDecide(archivePath string, config *Config, logger *Logger, procErr error) bool {
+	archiveName := filepath.Base(archivePath)
+	sidecarPath := attemptsSidecarPath(config.WatchDir, archiveName)
+
+	rec, err := loadAttemptRecord(sidecarPath, archiveName)
+	if err != nil {
+		logger.Info(fmt.Sprintf("Warning: failed to load attempts sidecar for %s: %v", archiveName, err))
+		rec = &AttemptRecord{Archive: archiveName}
+	}
+
+	class := classifyError(procErr)
+	rec.Attempts++
+	rec.LastError = procErr.Error()
+	rec.LastClass = string(class)
+	rec.LastAttempt = time.Now()
+
+	maxRetries := getEnvInt("MAX_RETRIES", 3)
+	if class == ErrorNonRetryable || rec.Attempts >= maxRetries {
+		logger.Info(fmt.Sprintf("✗ %s exhausted retries (%d/%d, class=%s), quarantining", archiveName, rec.Attempts, maxRetries, class))
+		os.Remove(sidecarPath)
+		return true
+	}
+
+	backoff := retryBackoff(rec.Attempts)
+	rec.NextRetryAt = time.Now().Add(backoff)
+	if err := saveAttemptRecord(sidecarPath, rec); err != nil {
+		logger.Info(fmt.Sprintf("Warning: failed to write attempts sidecar for %s: %v", archiveName, err))
+	}
+	logger.Info(fmt.Sprintf("%s failed (attempt %d/%d, class=%s), will retry after %s", archiveName, rec.Attempts, maxRetries, class, backoff))
+	return false
+}
+
+// isInBackoff reports whether archivePath has an attempts sidecar whose
+// NextRetryAt is still in the future, so scan loops can skip it without
+// tight-looping on the same failure every cycle.
+func isInBackoff(archivePath string, config *Config) bool {
+	sidecarPath := attemptsSidecarPath(config.WatchDir, filepath.Base(archivePath))
+	rec, err := loadAttemptRecord(sidecarPath, filepath.Base(archivePath))
+	if err != nil || rec == nil {
+		return false
+	}
+	return rec.NextRetryAt.After(time.Now())
+}
+
+// clearAttemptRecord removes the attempts sidecar after a successful run, so
+// a later unrelated failure doesn't inherit a stale attempt count.
+func clearAttemptRecord(archivePath string, config *Config) {
+	os.Remove(attemptsSidecarPath(config.WatchDir, filepath.Base(archivePath)))
+}
+
+// rescanFailedForRetry, enabled via RETRY_FAILED=true, looks in FailedDir for
+// archives whose quarantine sidecar indicates a retryable error class and
+// moves them back into WatchDir for another attempt. Archives with no
+// sidecar, or a non-retryable class, are left alone.
+func rescanFailedForRetry(config *Config, logger *Logger) {
+	if !getEnvBool("RETRY_FAILED", false) {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(config.FailedDir, "*.tar.xz"))
+	if err != nil {
+		logger.Info(fmt.Sprintf("Warning: RETRY_FAILED scan of %s failed: %v", config.FailedDir, err))
+		return
+	}
+
+	for _, archivePath := range matches {
+		archiveName := filepath.Base(archivePath)
+		sidecarPath := archivePath + ".error"
+
+		data, err := os.ReadFile(sidecarPath)
+		if err != nil {
+			continue // no sidecar, nothing to classify on
+		}
+
+		if classifyError(fmt.Errorf("%s", string(data))) != ErrorRetryable {
+			continue
+		}
+
+		newPath := filepath.Join(config.WatchDir, archiveName)
+		if err := os.Rename(archivePath, newPath); err != nil {
+			logger.Info(fmt.Sprintf("Warning: RETRY_FAILED failed to move %s back to WatchDir: %v", archiveName, err))
+			continue
+		}
+		os.Remove(sidecarPath)
+		logger.Info(fmt.Sprintf("RETRY_FAILED: moved %s back to %s for another attempt", archiveName, config.WatchDir))
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// InfluxDB1Sink writes to an InfluxDB 1.x `/write?db=` endpoint using the
+// same line-protocol format as the v2 sink, for operators who haven't
+// migrated off 1.x yet.
+type InfluxDB1Sink struct {
+	config *Config
+	logger *Logger
+	client *http.Client
+	lines  []string
+}
+
+// NewInfluxDB1Sink builds an InfluxDB1Sink targeting config.InfluxDBURL/write,
+// using config.InfluxDBBucket as the 1.x database name.
+func NewInfluxDB1Sink(config *Config, logger *Logger) (Sink, error) {
+	return &InfluxDB1Sink{
+		config: config,
+		logger: logger,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (s *InfluxDB1Sink) WritePoint(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	s.lines = append(s.lines, lineProtocol(measurement, tags, fields, ts))
+	return nil
+}
+
+func (s *InfluxDB1Sink) Flush() error {
+	if len(s.lines) == 0 {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/write?db=%s&precision=ns", strings.TrimRight(s.config.InfluxDBURL, "/"), s.config.InfluxDBBucket)
+	body := strings.NewReader(strings.Join(s.lines, "\n"))
+
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("influxdb1 sink: %w", err)
+	}
+	if s.config.InfluxDBToken != "" {
+		req.Header.Set("Authorization", "Token "+s.config.InfluxDBToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influxdb1 sink: write failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(resp.Body)
+		return fmt.Errorf("influxdb1 sink: write returned %s: %s", resp.Status, buf.String())
+	}
+
+	s.lines = s.lines[:0]
+	return nil
+}
+
+func (s *InfluxDB1Sink) Close() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// InfluxDB2Sink is the default sink: it stages points through the durable
+// per-archive WAL (see wal.go) and delivers them with retry/dead-letter on
+// Flush, exactly as processArchive did before output sinks were pluggable.
+type InfluxDB2Sink struct {
+	config      *Config
+	archiveName string
+	logger      *Logger
+	wal         *WAL
+}
+
+// NewInfluxDB2Sink opens the WAL file for archiveName.
+func NewInfluxDB2Sink(config *Config, archiveName string, logger *Logger) (Sink, error) {
+	wal, err := OpenWAL(config.LogDir, archiveName)
+	if err != nil {
+		return nil, err
+	}
+	return &InfluxDB2Sink{config: config, archiveName: archiveName, logger: logger, wal: wal}, nil
+}
+
+func (s *InfluxDB2Sink) WritePoint(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	return s.wal.Append(lineProtocol(measurement, tags, fields, ts))
+}
+
+func (s *InfluxDB2Sink) Flush() error {
+	s.wal.Close()
+
+	delivered, deadLettered, err := FlushWAL(context.Background(), s.config, s.archiveName, s.logger)
+	if err != nil {
+		return fmt.Errorf("influxdb2 sink: WAL flush failed: %w", err)
+	}
+	if deadLettered > 0 {
+		// A distinct, non-retryable error: it gets the archive quarantined
+		// to FailedDir on this attempt rather than retried (classifyError),
+		// since a retry would re-parse and re-export the WHOLE archive,
+		// re-delivering the points already written above. The dead-lettered
+		// lines are durably parked under LogDir/deadletter/ for
+		// `pcp-parser replay` to resend once InfluxDB recovers.
+		s.logger.Critical(fmt.Sprintf("influxdb2 sink: %d point(s) dead-lettered after exhausting retries (delivered %d)", deadLettered, delivered), "archive", s.archiveName)
+		return &DeadLetterError{Delivered: delivered, DeadLettered: deadLettered}
+	}
+	return nil
+}
+
+func (s *InfluxDB2Sink) Close() error {
+	return nil
+}
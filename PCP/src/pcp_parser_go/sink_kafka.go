@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaSink writes one JSON message per point to a Kafka topic, for
+// operators who want PCP metrics flowing into an existing Kafka-backed
+// pipeline instead of (or alongside) a time-series database.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+// kafkaPoint is the JSON shape written per message.
+type kafkaPoint struct {
+	Measurement string                 `json:"measurement"`
+	Tags        map[string]string      `json:"tags"`
+	Fields      map[string]interface{} `json:"fields"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+// NewKafkaSink connects to KAFKA_BROKERS (comma-separated) and writes to
+// KAFKA_TOPIC (default "pcp-metrics").
+func NewKafkaSink(config *Config, logger *Logger) (Sink, error) {
+	brokers := strings.Split(getEnv("KAFKA_BROKERS", "localhost:9092"), ",")
+	topic := getEnv("KAFKA_TOPIC", "pcp-metrics")
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.LeastBytes{},
+		BatchTimeout: 1 * time.Second,
+	}
+
+	return &KafkaSink{writer: writer}, nil
+}
+
+func (s *KafkaSink) WritePoint(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	payload, err := json.Marshal(kafkaPoint{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: ts})
+	if err != nil {
+		return fmt.Errorf("kafka sink: marshal failed: %w", err)
+	}
+
+	return s.writer.WriteMessages(context.Background(), kafka.Message{Value: payload})
+}
+
+// Flush is a no-op: kafka-go's Writer delivers (and can be configured to
+// batch) on every WriteMessages call already.
+func (s *KafkaSink) Flush() error {
+	return nil
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sink is a destination for decoded PCP points. Archives are exported
+// through one or more Sinks (see OUTPUT_SINK), the same way Telegraf-style
+// collectors let one input fan out to several time-series backends.
+type Sink interface {
+	// WritePoint stages one point (the same "row" of metrics a pmrep CSV
+	// line or archive sample produces) for delivery.
+	WritePoint(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error
+
+	// Flush delivers everything staged so far. It returns an error if any
+	// part of the batch could not be delivered, so callers can decide
+	// whether the archive should be retried or quarantined.
+	Flush() error
+
+	// Close releases any connections/handles held by the sink.
+	Close() error
+}
+
+// MultiSink fans a point out to every configured Sink and is itself a Sink,
+// so callers don't need to special-case "one sink" vs "several".
+type MultiSink struct {
+	sinks []Sink
+}
+
+// BuildSinks parses OUTPUT_SINK (comma-separated, default "influxdb2") and
+// constructs the corresponding Sink for each name. archiveName is only used
+// by sinks that need a per-archive handle (currently just influxdb2, for
+// its WAL).
+func BuildSinks(config *Config, archiveName string, logger *Logger) (*MultiSink, error) {
+	names := config.OutputSinks
+	if len(names) == 0 {
+		names = []string{"influxdb2"}
+	}
+
+	var sinks []Sink
+	for _, name := range names {
+		var sink Sink
+		var err error
+
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "influxdb2":
+			sink, err = NewInfluxDB2Sink(config, archiveName, logger)
+		case "influxdb1":
+			sink, err = NewInfluxDB1Sink(config, logger)
+		case "prometheus":
+			sink, err = NewPrometheusRemoteWriteSink(config, logger)
+		case "kafka":
+			sink, err = NewKafkaSink(config, logger)
+		case "file":
+			sink = NewFileSink()
+		default:
+			err = fmt.Errorf("unknown OUTPUT_SINK %q", name)
+		}
+
+		if err != nil {
+			// Close anything already opened before returning the error.
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to build sink %q: %w", name, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return &MultiSink{sinks: sinks}, nil
+}
+
+func (m *MultiSink) WritePoint(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) error {
+	var firstErr error
+	for _, sink := range m.sinks {
+		if err := sink.WritePoint(measurement, tags, fields, ts); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *MultiSink) Flush() error {
+	var firstErr error
+	for _, sink := range m.sinks {
+		if err := sink.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *MultiSink) Close() error {
+	var firstErr error
+	for _, sink := range m.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// TriggerServer replaces the old trigger-file poll loop with an HTTP API:
+// POST /trigger runs a processing pass synchronously and returns a JSON
+// summary, GET /status reports the in-progress state and the last run's
+// result. SIGHUP (wired up by main) drives the same RunTrigger path, so the
+// HTTP endpoint and the signal share one mutex and one notion of "busy".
+type TriggerServer struct {
+	config *Config
+	logger *Logger
+
+	mu          sync.Mutex
+	inProgress  bool
+	lastRunAt   time.Time
+	lastSummary *ProcessSummary
+	lastErr     string
+}
+
+// NewTriggerServer builds a TriggerServer for config/logger. Call Start to
+// begin listening.
+func NewTriggerServer(config *Config, logger *Logger) *TriggerServer {
+	return &TriggerServer{config: config, logger: logger}
+}
+
+// RunTrigger runs processAllArchives if no run is already in progress. It is
+// safe to call concurrently from the HTTP handler and the SIGHUP handler.
+func (s *TriggerServer) RunTrigger() (*ProcessSummary, error) {
+	s.mu.Lock()
+	if s.inProgress {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("a processing run is already in progress")
+	}
+	s.inProgress = true
+	s.mu.Unlock()
+
+	summary, err := processAllArchives(s.config, s.logger)
+
+	s.mu.Lock()
+	s.inProgress = false
+	s.lastRunAt = time.Now()
+	s.lastSummary = summary
+	if err != nil {
+		s.lastErr = err.Error()
+	} else {
+		s.lastErr = ""
+	}
+	s.mu.Unlock()
+
+	return summary, err
+}
+
+func (s *TriggerServer) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.logger.Info("TRIGGER DETECTED - Starting processing...", "source", "http")
+
+	summary, err := s.RunTrigger()
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("Error during processing: %v", err))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (s *TriggerServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	status := struct {
+		InProgress bool            `json:"in_progress"`
+		LastRunAt  *time.Time      `json:"last_run_at,omitempty"`
+		LastError  string          `json:"last_error,omitempty"`
+		LastRun    *ProcessSummary `json:"last_run,omitempty"`
+	}{
+		InProgress: s.inProgress,
+		LastError:  s.lastErr,
+		LastRun:    s.lastSummary,
+	}
+	if !s.lastRunAt.IsZero() {
+		t := s.lastRunAt
+		status.LastRunAt = &t
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Start listens on TRIGGER_LISTEN (default 127.0.0.1:8765) until ctx is
+// cancelled, at which point it shuts the server down gracefully.
+func (s *TriggerServer) Start(ctx context.Context) error {
+	addr := getEnv("TRIGGER_LISTEN", "127.0.0.1:8765")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/trigger", s.handleTrigger)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.logger.Info(fmt.Sprintf("Trigger server listening on %s (POST /trigger, GET /status, GET /metrics)", addr))
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	}
+}
+
+// watchSighup triggers a processing run on every SIGHUP, for operators who
+// prefer `kill -HUP` over curling the /trigger endpoint. It runs until ctx
+// is cancelled.
+func (s *TriggerServer) watchSighup(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			s.logger.Info("TRIGGER DETECTED - Starting processing...", "source", "SIGHUP")
+			if _, err := s.RunTrigger(); err != nil {
+				s.logger.Info(fmt.Sprintf("Error during processing: %v", err))
+			}
+		}
+	}
+}
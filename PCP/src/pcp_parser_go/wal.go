@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+// DeadLetterError reports that FlushWAL gave up on some points after
+// exhausting retries. It classifies as non-retryable (see classifyError) so
+// the archive is quarantined to FailedDir immediately rather than being
+// re-parsed and re-exported on the next cycle, which would re-deliver the
+// points that were already written before classifying it as success.
+type DeadLetterError struct {
+	Delivered    int
+	DeadLettered int
+}
+
+func (e *DeadLetterError) Error() string {
+	return fmt.Sprintf("%d point(s) dead-lettered after exhausting retries (delivered %d); run `pcp-parser replay` on the deadletter file once InfluxDB recovers", e.DeadLettered, e.Delivered)
+}
+
+// WAL is a durable per-archive write-ahead log of line-protocol points.
+// Points are appended here as they're built, so a network blip mid-archive
+// no longer means the archive gets marked processed with partial data: the
+// WAL survives until a flusher has delivered its points to InfluxDB or
+// written the undeliverable ones to the dead-letter file.
+type WAL struct {
+	path string
+	file *os.File
+}
+
+func walPath(logDir, archiveName string) string {
+	return filepath.Join(logDir, "wal", strings.TrimSuffix(archiveName, ".tar.xz")+".lp")
+}
+
+func deadLetterPath(logDir, archiveName string) string {
+	return filepath.Join(logDir, "deadletter", strings.TrimSuffix(archiveName, ".tar.xz")+".lp")
+}
+
+// OpenWAL creates (or truncates) the WAL file for archiveName under
+// LogDir/wal/.
+func OpenWAL(logDir, archiveName string) (*WAL, error) {
+	path := walPath(logDir, archiveName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WAL file: %w", err)
+	}
+
+	return &WAL{path: path, file: file}, nil
+}
+
+// Append writes one line-protocol point to the WAL.
+func (w *WAL) Append(line string) error {
+	_, err := w.file.WriteString(line + "\n")
+	return err
+}
+
+// Close flushes and closes the WAL file handle (the file itself is left on
+// disk until the flusher has confirmed delivery).
+func (w *WAL) Close() error {
+	return w.file.Close()
+}
+
+// lineProtocol encodes a single point in InfluxDB line-protocol format.
+// Only float64 fields are produced by this codebase today, so that's all
+// this escapes/formats; it's kept generic enough for string/int64 too.
+func lineProtocol(measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(escapeLP(measurement))
+
+	tagKeys := make([]string, 0, len(tags))
+	for k := range tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		sb.WriteString(",")
+		sb.WriteString(escapeLP(k))
+		sb.WriteString("=")
+		sb.WriteString(escapeLP(tags[k]))
+	}
+
+	sb.WriteString(" ")
+
+	fieldKeys := make([]string, 0, len(fields))
+	for k := range fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for i, k := range fieldKeys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(escapeLP(k))
+		sb.WriteString("=")
+		sb.WriteString(formatLPValue(fields[k]))
+	}
+
+	sb.WriteString(" ")
+	sb.WriteString(strconv.FormatInt(ts.UnixNano(), 10))
+
+	return sb.String()
+}
+
+func escapeLP(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, "=", "\\=")
+	s = strings.ReplaceAll(s, " ", "\\ ")
+	return s
+}
+
+func formatLPValue(v interface{}) string {
+	switch val := v.(type) {
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(val, 10) + "i"
+	case string:
+		return `"` + strings.ReplaceAll(val, `"`, `\"`) + `"`
+	default:
+		return fmt.Sprintf(`"%v"`, val)
+	}
+}
+
+// FlushWAL streams the WAL for archiveName to InfluxDB using the blocking
+// write API, retrying the whole remaining batch with exponential backoff
+// (INFLUX_MAX_RETRIES attempts, capped at INFLUX_BACKOFF_MAX between
+// attempts). Lines that still fail after retries are exhausted are written
+// to LogDir/deadletter/<archive>.lp instead of being dropped. It returns
+// the number of points delivered and the number dead-lettered.
+func FlushWAL(ctx context.Context, config *Config, archiveName string, logger *Logger) (delivered int, deadLettered int, err error) {
+	path := walPath(config.LogDir, archiveName)
+	lines, err := readLines(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read WAL for %s: %w", archiveName, err)
+	}
+	if len(lines) == 0 {
+		return 0, 0, nil
+	}
+
+	client := influxdb2.NewClient(config.InfluxDBURL, config.InfluxDBToken)
+	defer client.Close()
+	writeAPI := client.WriteAPIBlocking(config.InfluxDBOrg, config.InfluxDBBucket)
+
+	maxRetries := getEnvInt("INFLUX_MAX_RETRIES", 5)
+	backoffMax := time.Duration(getEnvInt("INFLUX_BACKOFF_MAX", 30)) * time.Second
+
+	remaining := lines
+	for attempt := 0; attempt <= maxRetries && len(remaining) > 0; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(math.Pow(2, float64(attempt))) * time.Second
+			if backoff > backoffMax {
+				backoff = backoffMax
+			}
+			logger.Info("Retrying WAL flush", "archive", archiveName, "attempt", attempt, "backoff_seconds", backoff.Seconds())
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return delivered, len(remaining), ctx.Err()
+			}
+		}
+
+		if writeErr := writeAPI.WriteRecord(ctx, remaining...); writeErr != nil {
+			logger.Warn("WAL flush attempt failed", "archive", archiveName, "attempt", attempt, "error", writeErr.Error())
+			continue
+		}
+
+		delivered += len(remaining)
+		remaining = nil
+	}
+
+	keepWAL := false
+	if len(remaining) > 0 {
+		if dlErr := writeDeadLetter(config.LogDir, archiveName, remaining); dlErr != nil {
+			logger.Error("Failed to write dead-letter file", "archive", archiveName, "error", dlErr.Error())
+			// The WAL is then the only copy of these lines, so it stays on disk.
+			keepWAL = true
+		}
+		deadLettered = len(remaining)
+		logger.Error("Points dead-lettered after exhausting retries", "archive", archiveName, "count", deadLettered)
+	}
+
+	if !keepWAL {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed to remove flushed WAL file", "archive", archiveName, "error", err.Error())
+		}
+	}
+
+	return delivered, deadLettered, nil
+}
+
+func writeDeadLetter(logDir, archiveName string, lines []string) error {
+	path := deadLetterPath(logDir, archiveName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	const maxCapacity = 1024 * 1024
+	scanner.Buffer(make([]byte, maxCapacity), maxCapacity)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// runReplayCommand implements the `pcp-parser replay <file>` subcommand.
+func runReplayCommand(config *Config, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: pcp-parser replay <wal-or-deadletter-file>")
+		os.Exit(2)
+	}
+
+	logPath := filepath.Join(config.LogDir, "pcp_parser_go.log")
+	logger, err := NewLogger(logPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to set up logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Close()
+
+	if err := ReplayDeadLetter(args[0], config, logger); err != nil {
+		logger.Error("Replay failed", "file", args[0], "error", err.Error())
+		os.Exit(1)
+	}
+}
+
+// ReplayDeadLetter re-reads a WAL or dead-letter line-protocol file and
+// re-sends every line to InfluxDB, for the `pcp-parser replay <file>` CLI
+// subcommand used to recover after an InfluxDB outage without re-parsing
+// the original archive.
+func ReplayDeadLetter(path string, config *Config, logger *Logger) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(lines) == 0 {
+		logger.Info("Nothing to replay", "file", path)
+		return nil
+	}
+
+	client := influxdb2.NewClient(config.InfluxDBURL, config.InfluxDBToken)
+	defer client.Close()
+	writeAPI := client.WriteAPIBlocking(config.InfluxDBOrg, config.InfluxDBBucket)
+
+	ctx := context.Background()
+	if err := writeAPI.WriteRecord(ctx, lines...); err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+
+	logger.Info("Replay complete", "file", path, "points", len(lines))
+	return nil
+}